@@ -0,0 +1,177 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Package breaker implements a http.RoundTripper circuit breaker.
+//
+// A Transport starts closed. Once FailureThreshold consecutive failures (as
+// reported by ShouldTrip) have been seen, the breaker opens and
+// rejects requests immediately with ErrOpen, without invoking the
+// underlying transport, until OpenDuration has elapsed. It then moves
+// to a half-open state that lets up to HalfOpenProbes requests
+// through as trial probes: a failed probe reopens the circuit, while
+// enough successful probes close it again.
+//
+//     b := &breaker.Transport{
+//         FailureThreshold: 5,
+//         OpenDuration:     10 * time.Second,
+//         HalfOpenProbes:   1,
+//         Transport: http.DefaultTransport,
+//     }
+//     r := retry.Transport{
+//         // See github.com/tvastar/http/retry for details
+//         Backoff: backoff.NewExponentialBackOff(),
+//         ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+//             return err, err != breaker.ErrOpen && err != nil && !lastAttempt
+//         },
+//         Transport: b,
+//     }
+//     client := http.Client{Transport: r}
+//
+// retry.Transport must be the outer transport, with breaker.Transport
+// nested inside it (not the other way around): ShouldRetry only sees
+// what comes out of a single call to its Transport's RoundTrip, so
+// breaker.Transport needs to be on the inside for ErrOpen to reach
+// it. Wrapping it the other way around means retry.Transport's whole
+// attempt loop runs inside one breaker-gated call, and ShouldRetry
+// never observes ErrOpen at all.
+package breaker
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by RoundTrip without touching the underlying
+// transport whenever the circuit is open.
+var ErrOpen = errors.New("breaker: circuit open")
+
+// state is the internal circuit state.
+type state int
+
+const (
+	closed state = iota
+	open
+	halfOpen
+)
+
+// Transport implements the http circuit-breaker middleware.
+type Transport struct {
+	// FailureThreshold is the number of consecutive failures (as
+	// reported by ShouldTrip) that opens the circuit. Zero disables
+	// tripping entirely.
+	FailureThreshold int
+
+	// OpenDuration is how long the circuit stays open before the
+	// first half-open probe is let through.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of consecutive successful probes
+	// required, while half-open, to close the circuit again. Zero is
+	// treated as 1.
+	HalfOpenProbes int
+
+	// ShouldTrip decides whether a response/error pair counts as a
+	// failure. It mirrors retry.Transport's ShouldRetry signature.
+	// The default treats any non-nil err as a failure.
+	ShouldTrip func(res *http.Response, err error) bool
+
+	Transport http.RoundTripper // the base transport
+
+	mu       sync.Mutex
+	state    state
+	failures int
+	admitted int
+	probes   int
+	openedAt time.Time
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.allow() {
+		return nil, ErrOpen
+	}
+
+	res, err := t.Transport.RoundTrip(req)
+
+	shouldTrip := t.ShouldTrip
+	if shouldTrip == nil {
+		shouldTrip = func(res *http.Response, err error) bool { return err != nil }
+	}
+
+	t.record(!shouldTrip(res, err))
+	return res, err
+}
+
+// allow reports whether a request may proceed, transitioning the
+// circuit from open to half-open once OpenDuration has elapsed.
+func (t *Transport) allow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.state {
+	case open:
+		if time.Since(t.openedAt) < t.OpenDuration {
+			return false
+		}
+		t.state = halfOpen
+		t.probes = 0
+		t.admitted = 0
+		fallthrough
+	case halfOpen:
+		if t.admitted >= t.halfOpenProbes() {
+			return false
+		}
+		t.admitted++
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the circuit state given whether the last request
+// succeeded.
+func (t *Transport) record(success bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == halfOpen {
+		t.probes++
+		if !success {
+			t.trip()
+			return
+		}
+		if t.probes >= t.halfOpenProbes() {
+			t.state = closed
+			t.failures = 0
+		}
+		return
+	}
+
+	if success {
+		t.failures = 0
+		return
+	}
+
+	t.failures++
+	if t.FailureThreshold > 0 && t.failures >= t.FailureThreshold {
+		t.trip()
+	}
+}
+
+// trip opens the circuit. Callers must hold t.mu.
+func (t *Transport) trip() {
+	t.state = open
+	t.openedAt = time.Now()
+	t.failures = 0
+	t.probes = 0
+}
+
+func (t *Transport) halfOpenProbes() int {
+	if t.HalfOpenProbes <= 0 {
+		return 1
+	}
+	return t.HalfOpenProbes
+}
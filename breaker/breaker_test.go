@@ -0,0 +1,117 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package breaker_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/tvastar/http/breaker"
+	"github.com/tvastar/http/retry"
+
+	"github.com/cenkalti/backoff"
+)
+
+func Example() {
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	b := &breaker.Transport{
+		FailureThreshold: 2,
+		OpenDuration:     10 * time.Millisecond,
+		ShouldTrip: func(res *http.Response, err error) bool {
+			return err != nil || res.StatusCode >= 500
+		},
+		Transport: http.DefaultTransport,
+	}
+	client := http.Client{Transport: b}
+
+	get := func() (int, error) {
+		req, err := http.NewRequest("GET", server.URL, nil)
+		if err != nil {
+			return 0, err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		return res.StatusCode, nil
+	}
+
+	statuses := []string{}
+	for i := 0; i < 2; i++ {
+		_, err := get()
+		statuses = append(statuses, fmt.Sprint(err))
+	}
+
+	// circuit is now open: the server isn't even contacted
+	_, err := get()
+	statuses = append(statuses, fmt.Sprint(errors.Is(err, breaker.ErrOpen)))
+
+	// wait for the open duration to elapse and fix the backend
+	time.Sleep(20 * time.Millisecond)
+	fail = false
+	status, err := get()
+	statuses = append(statuses, fmt.Sprint(status, err))
+
+	fmt.Println(statuses)
+
+	// Output: [<nil> <nil> true 200 <nil>]
+}
+
+// ExampleTransport_withRetry shows the only composition order in
+// which retry.ShouldRetry actually observes ErrOpen: retry.Transport
+// on the outside, breaker.Transport nested inside it.
+func ExampleTransport_withRetry() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	b := &breaker.Transport{
+		FailureThreshold: 1,
+		OpenDuration:     time.Minute,
+		ShouldTrip: func(res *http.Response, err error) bool {
+			return err != nil || res.StatusCode >= 500
+		},
+		Transport: http.DefaultTransport,
+	}
+
+	attempts := 0
+	sawErrOpen := false
+	r := retry.Transport{
+		Backoff: backoff.NewExponentialBackOff(),
+		ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+			attempts++
+			if errors.Is(err, breaker.ErrOpen) {
+				sawErrOpen = true
+			}
+			return err, attempts < 3 && !lastAttempt
+		},
+		Transport: b,
+	}
+	r.Backoff.InitialInterval = time.Millisecond
+	r.Backoff.MaxElapsedTime = time.Second
+
+	client := http.Client{Transport: r}
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		panic(err)
+	}
+	_, err = client.Do(req)
+	fmt.Println(attempts, sawErrOpen, errors.Is(err, breaker.ErrOpen))
+
+	// Output: 3 true true
+}
@@ -0,0 +1,233 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Package codec provides a pluggable encode/decode abstraction for
+// HTTP request and response bodies, so the Body/Transport machinery
+// isn't tied to encoding/json and "application/json".
+//
+// A Codec is registered against the Content-Type it handles:
+//
+//     codec.Register(myCodec)
+//
+// Body uses a Codec directly to build a request:
+//
+//     req, err := codec.NewRequest("POST", url, codec.Body(codec.JSON, v))
+//
+// while Transport looks one up by inspecting the response's
+// Content-Type header, which makes chaining with
+// github.com/tvastar/http/retry work for non-JSON APIs too:
+//
+//     client := &http.Client{
+//         Transport: codec.Transport{
+//             Result:    &output,
+//             Transport: retry.Transport{
+//                 Backoff:   backoff.NewExponentialBackOff(),
+//                 Transport: http.DefaultTransport,
+//             },
+//         },
+//     }
+//
+// github.com/tvastar/http/json is built on top of this package:
+// json.Body is sugar for codec.Body(codec.JSON, v).
+//
+// JSON and form-urlencoded codecs are registered by default. msgpack
+// and protobuf are deliberately NOT implemented here: doing so
+// properly needs a vendored encoder library, which this repo doesn't
+// otherwise depend on. What this package provides for those formats
+// is the extension point: implement Codec for the format of your
+// choice and call Register, and it works with both Body and
+// Transport exactly like JSON and Form do.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Codec marshals and unmarshals values for a specific Content-Type.
+type Codec interface {
+	// ContentType is the MIME type this codec handles, and the
+	// value set on requests built with Body.
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSON is the codec for "application/json", backed by encoding/json.
+var JSON Codec = jsonCodec{}
+
+// Form is the codec for "application/x-www-form-urlencoded". It
+// marshals via github.com/google/go-querystring/query; Unmarshal only
+// supports decoding into a *url.Values since, unlike JSON, there is
+// no general form-to-struct decoder in this repo.
+var Form Codec = formCodec{}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Codec{}
+)
+
+func init() {
+	Register(JSON)
+	Register(Form)
+}
+
+// Register makes c available to Transport for its ContentType,
+// overwriting any codec previously registered for that type.
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[c.ContentType()] = c
+}
+
+// Lookup returns the codec registered for contentType, if any.
+func Lookup(contentType string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := registry[contentType]
+	return c, ok
+}
+
+// Option is an option to pass to NewRequest.
+//
+// Custom options can either mutate the request or create a new
+// request and return that
+type Option func(req *http.Request) (*http.Request, error)
+
+// NewRequest creates a new http Request with the provided options.
+func NewRequest(method, url string, options ...Option) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, nil)
+	for kk := 0; kk < len(options) && err == nil; kk++ {
+		req, err = options[kk](req)
+	}
+	return req, err
+}
+
+// Body updates the request to use c's encoding of v, and sets the
+// Content-Type header to c.ContentType().
+//
+// It also sets req.GetBody so that transports which retry requests
+// (such as github.com/tvastar/http/retry) can replay the body on
+// each attempt.
+func Body(c Codec, v interface{}) Option {
+	return func(req *http.Request) (*http.Request, error) {
+		body, err := c.Marshal(v)
+		req.ContentLength = int64(len(body))
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Header.Set("Content-Type", c.ContentType())
+		return req, err
+	}
+}
+
+// Transport implements a pluggable-codec HTTP transport.
+//
+// This wraps another transport and only parses the response. If a
+// Codec is registered for the response's Content-Type, the body is
+// decoded into Result using that codec. Note that Result must be a
+// reference type (i.e. something that can be passed to the codec's
+// Unmarshal). Responses with no registered codec are passed through
+// untouched.
+type Transport struct {
+	Result    interface{}       // where the result is stored
+	Transport http.RoundTripper // the base transport
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	return Decode(res, t.Result)
+}
+
+// Decode looks up the Codec registered for res's Content-Type and
+// unmarshals its body into target, which must be a reference type
+// (i.e. something that can be passed to the codec's Unmarshal). If no
+// codec is registered for that Content-Type, res is returned
+// unchanged. This is the decode step Transport.RoundTrip performs;
+// it is also exported so other transports can reuse it against a
+// response they already have, without re-issuing the request
+// (github.com/tvastar/http/json's Transport uses DecodeWith for this).
+func Decode(res *http.Response, target interface{}) (*http.Response, error) {
+	ct, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return res, nil
+	}
+
+	c, ok := Lookup(ct)
+	if !ok {
+		return res, nil
+	}
+
+	return DecodeWith(res, c, target)
+}
+
+// DecodeWith is like Decode but uses c directly instead of looking
+// one up from res's Content-Type header. The response body is
+// buffered and restored so it can still be read afterwards.
+func DecodeWith(res *http.Response, c Codec, target interface{}) (*http.Response, error) {
+	defer res.Body.Close()
+	buf, err := ioutil.ReadAll(res.Body)
+	res.Body = ioutil.NopCloser(bytes.NewBuffer(buf))
+	if err != nil {
+		return res, err
+	}
+
+	return res, c.Unmarshal(buf, target)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type formCodec struct{}
+
+func (formCodec) ContentType() string { return "application/x-www-form-urlencoded" }
+
+func (formCodec) Marshal(v interface{}) ([]byte, error) {
+	val, err := query.Values(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(val.Encode()), nil
+}
+
+func (formCodec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	out, ok := v.(*url.Values)
+	if !ok {
+		return fmt.Errorf("codec: form decoding into %T is not supported; use *url.Values", v)
+	}
+	*out = values
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package codec_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/tvastar/http/codec"
+)
+
+func Example() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprint(w, `{"hello":42}`)
+	}))
+	defer server.Close()
+
+	var output map[string]interface{}
+	req, err := codec.NewRequest(
+		"POST",
+		server.URL,
+		codec.Body(codec.JSON, map[string]interface{}{"hello": 42}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	client := &http.Client{
+		Transport: codec.Transport{
+			Result:    &output,
+			Transport: http.DefaultTransport,
+		},
+	}
+	_, err = client.Do(req)
+	fmt.Println(output, err)
+
+	// Output: map[hello:42] <nil>
+}
+
+func ExampleForm() {
+	args := struct {
+		Foo int  `url:"foo"`
+		Bar bool `url:"bar"`
+	}{42, true}
+
+	req, err := codec.NewRequest("POST", "http://localhost/boo", codec.Body(codec.Form, args))
+	fmt.Println(req.Header.Get("Content-Type"), err)
+
+	// Output: application/x-www-form-urlencoded <nil>
+}
@@ -53,17 +53,49 @@
 //      }
 //      res, err := client.Do(req)
 //      // if there was no error, output will be filled in!
+//
+// Body and Transport are thin JSON-flavored sugar over
+// github.com/tvastar/http/codec: Body(v) is codec.Body(codec.JSON, v),
+// and a Transport with no Decode set behaves like a codec.Transport
+// restricted to "application/json", except that only 2xx responses
+// are decoded into Result.
+//
+// Setting ErrorResult (or ErrorFor) lets Transport decode non-2xx
+// responses too: the body is decoded into that target and returned
+// as a *HTTPError. For retry.ShouldRetry to be able to inspect that
+// error, retry.Transport must be the outer transport with
+// json.Transport nested inside it (the reverse of the basic example
+// above, which only decodes successful responses and so doesn't care
+// about ordering):
+//
+//      var apiErr struct{ Message string }
+//      client := &http.Client{
+//          Transport: retry.Transport{
+//              ShouldRetry: /* inspect err.(*json.HTTPError) here */,
+//              Transport: json.Transport{
+//                  Result:      &output,
+//                  ErrorResult: &apiErr,
+//                  Transport:   http.DefaultTransport,
+//              },
+//          },
+//      }
+//
+// Nesting it the other way (json.Transport outer, retry.Transport
+// inner, as in the basic example) means retry.Transport's whole
+// attempt loop runs inside one call to json.Transport's underlying
+// transport, and the *HTTPError is only constructed after that loop
+// returns — ShouldRetry never sees it.
 package json
 
 import (
-	"bytes"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"log"
 	"mime"
 	"net/http"
 
 	"github.com/google/go-querystring/query"
+	"github.com/tvastar/http/codec"
 )
 
 // NewRequest creates a new http Request with the provided options.
@@ -81,21 +113,16 @@ func NewRequest(method, url string, options ...Option) (*http.Request, error) {
 //
 // Custom options can either mutate the request or create a new
 // request and return that
-type Option func(req *http.Request) (*http.Request, error)
+type Option = codec.Option
 
 // Body updates the request to use the JSON encoding of the provided
-// value. It also sets the Content-Type value to "application/json"
+// value. It also sets the Content-Type value to "application/json".
+//
+// It also sets req.GetBody so that transports which retry requests
+// (such as github.com/tvastar/http/retry) can replay the body on
+// each attempt.
 func Body(v interface{}) Option {
-	return func(req *http.Request) (*http.Request, error) {
-		var buf bytes.Buffer
-		enc := json.NewEncoder(&buf)
-		enc.SetEscapeHTML(false)
-		err := enc.Encode(v)
-		req.ContentLength = int64(buf.Len())
-		req.Body = ioutil.NopCloser(&buf)
-		req.Header.Set("Content-Type", "application/json")
-		return req, err
-	}
+	return codec.Body(codec.JSON, v)
 }
 
 // Query updates the query with the provided args using standard URL
@@ -121,9 +148,46 @@ func Query(v interface{}) Option {
 // If the response has the content type `application/json`, then the
 // response body is decoded into `Result`.  Note that `Result` must be
 // a reference type (i.e. something that can be passed to json.Unmarshal).
+//
+// For large payloads or NDJSON/JSON-lines endpoints, set Decode
+// instead of Result: it receives a *json.Decoder over the response
+// body and can consume it token-at-a-time or record-at-a-time (see
+// Lines for a NDJSON helper) instead of buffering the whole body into
+// memory. When Decode is set, the response body is consumed directly
+// and is not re-readable afterwards; Result is ignored.
+//
+// ErrorResult and ErrorFor only apply to non-2xx responses: when one
+// of them names a target, the body is decoded into it and RoundTrip
+// returns a *HTTPError instead of decoding into Result. When neither
+// names a target, a non-2xx response is returned undecoded: it is not
+// decoded into Result nor passed to Decode.
 type Transport struct {
-	Result    interface{}       // where the result is stored
-	Transport http.RoundTripper // the base transport
+	Result    interface{}               // where the result is stored
+	Decode    func(*json.Decoder) error // streaming alternative to Result
+	Transport http.RoundTripper         // the base transport
+
+	// ErrorResult is the target to decode a non-2xx response's body
+	// into. Ignored when ErrorFor is set.
+	ErrorResult interface{}
+
+	// ErrorFor picks the decode target for a non-2xx response based
+	// on its status code, for APIs that use different error
+	// envelopes per status. Returning nil leaves the response
+	// undecoded (RoundTrip returns res, nil).
+	ErrorFor func(status int) interface{}
+}
+
+// HTTPError is returned by Transport.RoundTrip when the response has
+// a non-2xx status and ErrorResult or ErrorFor named a target to
+// decode the body into.
+type HTTPError struct {
+	StatusCode int
+	Header     http.Header
+	Body       interface{} // the decoded error payload
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("json: unexpected status %d", e.StatusCode)
 }
 
 // RoundTrip implements the http.RoundTripper interface
@@ -139,12 +203,50 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return res, nil
 	}
 
-	defer res.Body.Close()
-	buf, err := ioutil.ReadAll(res.Body)
-	res.Body = ioutil.NopCloser(bytes.NewBuffer(buf))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return t.decodeError(res)
+	}
+
+	if t.Decode != nil {
+		defer res.Body.Close()
+		return res, t.Decode(json.NewDecoder(res.Body))
+	}
+
+	return codec.DecodeWith(res, codec.JSON, t.Result)
+}
+
+func (t Transport) decodeError(res *http.Response) (*http.Response, error) {
+	target := t.ErrorResult
+	if t.ErrorFor != nil {
+		target = t.ErrorFor(res.StatusCode)
+	}
+	if target == nil {
+		return res, nil
+	}
+
+	res, err := codec.DecodeWith(res, codec.JSON, target)
 	if err != nil {
 		return res, err
 	}
 
-	return res, json.Unmarshal(buf, t.Result)
+	return res, &HTTPError{StatusCode: res.StatusCode, Header: res.Header, Body: target}
+}
+
+// Lines returns a Decode function suitable for Transport.Decode that
+// reads the response body as NDJSON (newline-delimited JSON) and
+// invokes fn with each decoded record. Decoding stops at the first
+// error returned by fn or encountered while reading.
+func Lines(fn func(json.RawMessage) error) func(*json.Decoder) error {
+	return func(dec *json.Decoder) error {
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			if err := fn(raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 }
@@ -7,13 +7,18 @@ package json_test
 import (
 	"bytes"
 	gojson "encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/tvastar/http/json"
+	"github.com/tvastar/http/retry"
+
+	"github.com/cenkalti/backoff"
 )
 
 func Example() {
@@ -94,6 +99,113 @@ func ExampleNewRequest() {
 	// {"hello":42}
 }
 
+func ExampleTransport_decode() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":1}`+"\n"+`{"id":2}`+"\n"+`{"id":3}`+"\n")
+	}))
+	defer server.Close()
+
+	var ids []int
+	client := &http.Client{
+		Transport: json.Transport{
+			Decode: json.Lines(func(raw gojson.RawMessage) error {
+				var rec struct{ ID int }
+				if err := gojson.Unmarshal(raw, &rec); err != nil {
+					return err
+				}
+				ids = append(ids, rec.ID)
+				return nil
+			}),
+			Transport: http.DefaultTransport,
+		},
+	}
+
+	req, err := json.NewRequest("GET", server.URL)
+	if err != nil {
+		panic(err)
+	}
+	_, err = client.Do(req)
+	fmt.Println("Got:", ids, err)
+
+	// Output: Got: [1 2 3] <nil>
+}
+
+func ExampleTransport_errorResult() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message":"bad input"}`)
+	}))
+	defer server.Close()
+
+	var apiErr struct{ Message string }
+	client := &http.Client{
+		Transport: json.Transport{
+			ErrorResult: &apiErr,
+			Transport:   http.DefaultTransport,
+		},
+	}
+
+	req, err := json.NewRequest("GET", server.URL)
+	if err != nil {
+		panic(err)
+	}
+	_, err = client.Do(req)
+
+	var httpErr *json.HTTPError
+	ok := errors.As(err, &httpErr)
+	fmt.Println(ok, httpErr.StatusCode, apiErr.Message)
+
+	// Output: true 400 bad input
+}
+
+// ExampleTransport_withRetry shows the only composition order in
+// which retry.ShouldRetry actually observes *json.HTTPError:
+// retry.Transport on the outside, json.Transport nested inside it.
+func ExampleTransport_withRetry() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message":"bad input"}`)
+	}))
+	defer server.Close()
+
+	var apiErr struct{ Message string }
+	attempts := 0
+	sawHTTPError := false
+	r := retry.Transport{
+		Backoff: backoff.NewExponentialBackOff(),
+		ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+			attempts++
+			var httpErr *json.HTTPError
+			if errors.As(err, &httpErr) {
+				sawHTTPError = true
+			}
+			return err, attempts < 2 && !lastAttempt
+		},
+		Transport: json.Transport{
+			ErrorResult: &apiErr,
+			Transport:   http.DefaultTransport,
+		},
+	}
+	r.Backoff.InitialInterval = time.Millisecond
+	r.Backoff.MaxElapsedTime = time.Second
+
+	client := http.Client{Transport: r}
+	req, err := json.NewRequest("GET", server.URL)
+	if err != nil {
+		panic(err)
+	}
+	_, err = client.Do(req)
+
+	var httpErr *json.HTTPError
+	ok := errors.As(err, &httpErr)
+	fmt.Println(attempts, sawHTTPError, ok, httpErr.StatusCode, apiErr.Message)
+
+	// Output: 2 true true 400 bad input
+}
+
 func sampleQuery() interface{} {
 	return struct {
 		Foo  int  `url:"foo"`
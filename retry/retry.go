@@ -13,10 +13,31 @@
 //     }
 //     client := http.Client{Transport: r}
 //     res, err := client.Do(http.NewRequest("GET", url, nil))
+//
+// If RespectRetryAfter is set, responses with a 429 or 503 status
+// that carry a `Retry-After` header (or the common `X-RateLimit-Reset`
+// header) use that value for the next delay instead of
+// `b.NextBackOff()`, clamped to MaxRetryAfter when that is non-zero.
+//
+// Requests with a body are replayed on every attempt: if req.GetBody
+// is already set (e.g. by github.com/tvastar/http/json's Body
+// option), it is used to rewind the body before each retry.
+// Otherwise, when req.ContentLength is known and no larger than
+// MaxReplayBodySize, the body is buffered in memory before the first
+// attempt and a GetBody is installed. Bodies with an unknown length
+// (e.g. chunked uploads, where ContentLength is -1, or a non-nil Body
+// with a ContentLength of 0, which net/http also treats as unknown
+// for client requests) or larger than
+// MaxReplayBodySize are left as-is and so are only sent once, even
+// if ShouldRetry asks for a retry.
 package retry
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -27,8 +48,25 @@ type Transport struct {
 	Backoff     *backoff.ExponentialBackOff
 	ShouldRetry func(res *http.Response, err error, lastAttempt bool) (error, bool)
 	Transport   http.RoundTripper
+
+	// RespectRetryAfter, when true, makes the transport honor a
+	// `Retry-After` or `X-RateLimit-Reset` header on 429/503
+	// responses instead of using the backoff interval.
+	RespectRetryAfter bool
+
+	// MaxRetryAfter caps the delay derived from RespectRetryAfter.
+	// Zero means no cap.
+	MaxRetryAfter time.Duration
+
+	// MaxReplayBodySize caps how large a request body
+	// bufferBodyForReplay will buffer in memory to make it
+	// replayable. Zero uses defaultMaxReplayBodySize.
+	MaxReplayBodySize int64
 }
 
+// defaultMaxReplayBodySize is used when MaxReplayBodySize is zero.
+const defaultMaxReplayBodySize = 1 << 20 // 1 MiB
+
 // RoundTrip implements the http.RoundTripper interface
 func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	copy := *t.Backoff
@@ -42,10 +80,27 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		shouldRetry = t.ShouldRetry
 	}
 
-	for {
+	if err := bufferBodyForReplay(req, t.maxReplayBodySize()); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
 		res, err := t.Transport.RoundTrip(req)
 		delay := b.NextBackOff()
 		lastAttempt := delay == backoff.Stop
+		if t.RespectRetryAfter {
+			if d, ok := retryAfterDelay(res); ok {
+				delay = t.clampRetryAfter(d)
+			}
+		}
 		if err, ok := shouldRetry(res, err, lastAttempt); !ok {
 			return res, err
 		}
@@ -57,3 +112,77 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 	}
 }
+
+// bufferBodyForReplay ensures req.GetBody is set whenever req has a
+// body whose size is known and within maxSize, so that the retry loop
+// can rewind it before each attempt. If the caller (or an earlier
+// Option, such as json.Body) already set GetBody, the request is left
+// untouched. A body with an unknown length (req.ContentLength <= 0
+// with a non-nil Body) or one larger than maxSize is left unbuffered.
+func bufferBodyForReplay(req *http.Request, maxSize int64) error {
+	if req.GetBody != nil || req.Body == nil {
+		return nil
+	}
+	if req.ContentLength <= 0 || req.ContentLength > maxSize {
+		return nil
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return nil
+}
+
+func (t Transport) maxReplayBodySize() int64 {
+	if t.MaxReplayBodySize > 0 {
+		return t.MaxReplayBodySize
+	}
+	return defaultMaxReplayBodySize
+}
+
+func (t Transport) clampRetryAfter(d time.Duration) time.Duration {
+	if t.MaxRetryAfter > 0 && d > t.MaxRetryAfter {
+		return t.MaxRetryAfter
+	}
+	return d
+}
+
+// retryAfterDelay extracts a retry delay from a 429/503 response,
+// preferring the standard `Retry-After` header (either delta-seconds
+// or an HTTP-date) and falling back to `X-RateLimit-Reset` (a Unix
+// timestamp some APIs use instead).
+func retryAfterDelay(res *http.Response) (time.Duration, bool) {
+	if res == nil || (res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+
+	if v := res.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if when, err := http.ParseTime(v); err == nil {
+			if d := time.Until(when); d > 0 {
+				return d, true
+			}
+			return 0, true
+		}
+	}
+
+	if v := res.Header.Get("X-RateLimit-Reset"); v != "" {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if d := time.Until(time.Unix(ts, 0)); d > 0 {
+				return d, true
+			}
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
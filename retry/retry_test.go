@@ -5,8 +5,12 @@
 package retry_test
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"time"
 
 	"github.com/tvastar/http/retry"
@@ -36,3 +40,160 @@ func Example() {
 
 	// Output: Got: 3 false
 }
+
+func ExampleTransport_respectRetryAfter() {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := retry.Transport{
+		Backoff: backoff.NewExponentialBackOff(),
+		ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+			return err, res != nil && res.StatusCode == http.StatusTooManyRequests && !lastAttempt
+		},
+		RespectRetryAfter: true,
+		MaxRetryAfter:     time.Second,
+		Transport:         http.DefaultTransport,
+	}
+	r.Backoff.MaxElapsedTime = time.Second
+
+	client := http.Client{Transport: r}
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		panic(err)
+	}
+	res, err := client.Do(req)
+	fmt.Println("Got:", attempts, res.StatusCode, err)
+
+	// Output: Got: 3 200 <nil>
+}
+
+func ExampleTransport_bodyReplay() {
+	attempts := 0
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := retry.Transport{
+		Backoff: backoff.NewExponentialBackOff(),
+		ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+			return err, res != nil && res.StatusCode == http.StatusServiceUnavailable && !lastAttempt
+		},
+		Transport: http.DefaultTransport,
+	}
+	r.Backoff.InitialInterval = time.Millisecond
+	r.Backoff.MaxElapsedTime = time.Second
+
+	client := http.Client{Transport: r}
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader([]byte("hello")))
+	if err != nil {
+		panic(err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader([]byte("hello"))), nil
+	}
+	res, err := client.Do(req)
+	fmt.Println("Got:", attempts, bodies, res.StatusCode, err)
+
+	// Output: Got: 3 [hello hello hello] 200 <nil>
+}
+
+// ExampleTransport_bodyReplayAuto covers the case bodyReplay doesn't:
+// a request whose Body has no pre-existing GetBody (http.NewRequest
+// only sets one for a handful of known reader types), where
+// bufferBodyForReplay must buffer it automatically because its
+// ContentLength is known and within MaxReplayBodySize.
+func ExampleTransport_bodyReplayAuto() {
+	attempts := 0
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := retry.Transport{
+		Backoff: backoff.NewExponentialBackOff(),
+		ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+			return err, res != nil && res.StatusCode == http.StatusServiceUnavailable && !lastAttempt
+		},
+		Transport: http.DefaultTransport,
+	}
+	r.Backoff.InitialInterval = time.Millisecond
+	r.Backoff.MaxElapsedTime = time.Second
+
+	client := http.Client{Transport: r}
+	req, err := http.NewRequest("POST", server.URL, ioutil.NopCloser(bytes.NewReader([]byte("hello"))))
+	if err != nil {
+		panic(err)
+	}
+	req.ContentLength = 5 // unknown to http.NewRequest for this Body type
+
+	res, err := client.Do(req)
+	fmt.Println("Got:", attempts, bodies, res.StatusCode, err)
+
+	// Output: Got: 3 [hello hello hello] 200 <nil>
+}
+
+// ExampleTransport_bodyReplaySkipsOversizedBody shows that a body
+// larger than MaxReplayBodySize is left unbuffered, so the request is
+// only ever sent once: the first attempt reaches the server with the
+// real body, and the retry fails client-side (its Body is already
+// drained) instead of silently resending a truncated one.
+func ExampleTransport_bodyReplaySkipsOversizedBody() {
+	attempts := 0
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	r := retry.Transport{
+		Backoff: backoff.NewExponentialBackOff(),
+		ShouldRetry: func(res *http.Response, err error, lastAttempt bool) (error, bool) {
+			return err, res != nil && res.StatusCode == http.StatusServiceUnavailable && !lastAttempt
+		},
+		MaxReplayBodySize: 1, // "hello" (5 bytes) exceeds this
+		Transport:         http.DefaultTransport,
+	}
+	r.Backoff.InitialInterval = time.Millisecond
+	r.Backoff.MaxElapsedTime = time.Second
+
+	client := http.Client{Transport: r}
+	req, err := http.NewRequest("POST", server.URL, ioutil.NopCloser(bytes.NewReader([]byte("hello"))))
+	if err != nil {
+		panic(err)
+	}
+	req.ContentLength = 5
+
+	_, err = client.Do(req)
+	fmt.Println("Got:", attempts, bodies, err != nil)
+
+	// Output: Got: 1 [hello] true
+}